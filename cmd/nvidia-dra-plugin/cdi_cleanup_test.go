@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2023, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestCleanupStaleCDIFiles(t *testing.T) {
+	specDir := t.TempDir()
+
+	live := []string{"claim-live-1", "claim-live-2"}
+	stale := []string{"claim-stale-1", "claim-stale-2"}
+
+	allocated := make(map[string]bool)
+	for _, claimUID := range live {
+		allocated[claimUID] = true
+		writeTestCDISpec(t, specDir, claimUID)
+	}
+	for _, claimUID := range stale {
+		writeTestCDISpec(t, specDir, claimUID)
+	}
+
+	var wg sync.WaitGroup
+	errors := cleanupStaleCDIFiles(specDir, allocated, &wg, nil)
+
+	var errCount int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range errors {
+			t.Errorf("unexpected error cleaning up CDI files: %v", err)
+			errCount++
+		}
+	}()
+
+	wg.Wait()
+	close(errors)
+	<-done
+
+	if errCount != 0 {
+		t.Fatalf("expected no errors, got %d", errCount)
+	}
+
+	for _, claimUID := range live {
+		if _, err := os.Stat(filepath.Join(specDir, cdiSpecFileName(claimUID))); err != nil {
+			t.Errorf("expected live CDI spec for claim %v to remain: %v", claimUID, err)
+		}
+	}
+	for _, claimUID := range stale {
+		if _, err := os.Stat(filepath.Join(specDir, cdiSpecFileName(claimUID))); !os.IsNotExist(err) {
+			t.Errorf("expected stale CDI spec for claim %v to be removed, stat err: %v", claimUID, err)
+		}
+	}
+}
+
+func TestCleanupStaleCDIFilesMissingSpecDir(t *testing.T) {
+	specDir := filepath.Join(t.TempDir(), "never-created")
+
+	var wg sync.WaitGroup
+	errors := cleanupStaleCDIFiles(specDir, map[string]bool{}, &wg, nil)
+
+	var errCount int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range errors {
+			t.Errorf("unexpected error cleaning up a CDI spec dir that was never created: %v", err)
+			errCount++
+		}
+	}()
+
+	wg.Wait()
+	close(errors)
+	<-done
+
+	if errCount != 0 {
+		t.Fatalf("expected no errors, got %d", errCount)
+	}
+}
+
+func TestCdiClaimUIDFromSpecFileNameRoundTrips(t *testing.T) {
+	claimUID := "9f1e2d3c-live"
+	got, ok := cdiClaimUIDFromSpecFileName(cdiSpecFileName(claimUID))
+	if !ok || got != claimUID {
+		t.Fatalf("cdiClaimUIDFromSpecFileName(cdiSpecFileName(%q)) = (%q, %v), want (%q, true)", claimUID, got, ok, claimUID)
+	}
+
+	if _, ok := cdiClaimUIDFromSpecFileName("some-other-vendor.yaml"); ok {
+		t.Fatalf("expected a spec file from another vendor/class to not be recognized as a claim UID")
+	}
+}
+
+// TestCdiSpecFileNamePinnedToDeviceStateConvention locks cdiSpecFileName's
+// output to the literal name DeviceState.cdi writes
+// ("k8s.gpu.resource.nvidia.com-claim_<uid>.yaml"). Unlike the round-trip
+// test above, this catches a change to cdiVendor/cdiClass/cdiSpecFileSuffix
+// that cdiClaimUIDFromSpecFileName would silently follow along with, leaving
+// cleanup unable to find specs DeviceState.cdi actually wrote under the old
+// convention.
+func TestCdiSpecFileNamePinnedToDeviceStateConvention(t *testing.T) {
+	claimUID := "9f1e2d3c-live"
+	want := "k8s.gpu.resource.nvidia.com-claim_9f1e2d3c-live.yaml"
+	if got := cdiSpecFileName(claimUID); got != want {
+		t.Fatalf("cdiSpecFileName(%q) = %q, want %q (the literal name DeviceState.cdi writes)", claimUID, got, want)
+	}
+}
+
+// writeTestCDISpec seeds specDir with a CDI spec for claimUID, using the
+// exact same naming convention (cdiSpecFileName) the cleanup path parses.
+func writeTestCDISpec(t *testing.T, dir, claimUID string) {
+	t.Helper()
+	path := filepath.Join(dir, cdiSpecFileName(claimUID))
+	if err := os.WriteFile(path, []byte("cdiVersion: \"0.5.0\"\n"), 0644); err != nil {
+		t.Fatalf("error writing test CDI spec %q: %v", path, err)
+	}
+}
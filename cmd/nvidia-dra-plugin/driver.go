@@ -18,15 +18,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	resourceapi "k8s.io/api/resource/v1alpha2"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
-	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1alpha2"
+	drapbv1 "k8s.io/kubelet/pkg/apis/dra/v1alpha3"
+
+	"google.golang.org/grpc"
 
 	nascrd "github.com/NVIDIA/k8s-dra-driver/api/nvidia.com/resource/gpu/nas/v1alpha1"
 	nasclient "github.com/NVIDIA/k8s-dra-driver/api/nvidia.com/resource/gpu/nas/v1alpha1/client"
@@ -34,6 +44,34 @@ import (
 
 const (
 	CleanupTimeoutSecondsOnError = 5
+
+	// DefaultReconcileInterval is how often the periodic reconciliation loop
+	// runs as a safety net alongside the watch-based cleanup, in case the
+	// watch silently misses events.
+	DefaultReconcileInterval = 5 * time.Minute
+
+	// DefaultStaleClaimGracePeriod is how long a claim must be continuously
+	// observed as stale (prepared but no longer allocated) before it is
+	// actually unprepared. This avoids racing claims that are legitimately
+	// mid-preparation.
+	DefaultStaleClaimGracePeriod = 30 * time.Second
+
+	// cdiVendor and cdiClass are the CDI vendor/class pair DeviceState.cdi
+	// registers its kind under. Together with cdiSpecFileSuffix they spell
+	// out the file name DeviceState.cdi gives a claim's CDI spec, which the
+	// cleanup code builds and parses through cdiSpecFileName /
+	// cdiClaimUIDFromSpecFileName below. The write path does not call these
+	// helpers; TestCdiSpecFileNamePinnedToDeviceStateConvention pins them to
+	// the name it writes.
+	cdiVendor = "k8s.gpu.resource.nvidia.com"
+	cdiClass  = "claim"
+
+	// cdiSpecFileSuffix is the extension this driver writes CDI spec files
+	// with.
+	cdiSpecFileSuffix = ".yaml"
+
+	mpsQuitTimeout = 5 * time.Second
+	mpsTermTimeout = 5 * time.Second
 )
 
 type driver struct {
@@ -42,10 +80,26 @@ type driver struct {
 	nascrd    *nascrd.NodeAllocationState
 	nasclient *nasclient.Client
 	state     *DeviceState
+
+	watchLock sync.Mutex
+	watchSubs map[int]chan *resourceapi.ResourceModel
+	nextSubID int
+
+	reconcileInterval     time.Duration
+	staleClaimGracePeriod time.Duration
+
+	staleLock  sync.Mutex
+	staleSince map[string]time.Time
+
+	health *healthServer
 }
 
 func NewDriver(ctx context.Context, config *Config) (*driver, error) {
 	var d *driver
+
+	health := newHealthServer(config.flags.healthzAddress, config.flags.watchStalenessThreshold)
+	health.Start(ctx)
+
 	client := nasclient.New(config.nascrd, config.clientset.Nvidia.NasV1alpha1())
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		err := client.GetOrCreate(ctx)
@@ -73,10 +127,25 @@ func NewDriver(ctx context.Context, config *Config) (*driver, error) {
 			return err
 		}
 
+		reconcileInterval := config.flags.reconcileInterval
+		if reconcileInterval <= 0 {
+			reconcileInterval = DefaultReconcileInterval
+		}
+
+		staleClaimGracePeriod := config.flags.staleClaimGracePeriod
+		if staleClaimGracePeriod <= 0 {
+			staleClaimGracePeriod = DefaultStaleClaimGracePeriod
+		}
+
 		d = &driver{
-			nascrd:    config.nascrd,
-			nasclient: client,
-			state:     state,
+			nascrd:                config.nascrd,
+			nasclient:             client,
+			state:                 state,
+			watchSubs:             make(map[int]chan *resourceapi.ResourceModel),
+			reconcileInterval:     reconcileInterval,
+			staleClaimGracePeriod: staleClaimGracePeriod,
+			staleSince:            make(map[string]time.Time),
+			health:                health,
 		}
 
 		return nil
@@ -85,12 +154,23 @@ func NewDriver(ctx context.Context, config *Config) (*driver, error) {
 		return nil, err
 	}
 
+	// Reflects the NodeAllocationStateStatusReady transition performed above.
+	health.setReady(true)
+
+	pluginSocketPath := config.flags.pluginSocketPath
+	if pluginSocketPath == "" {
+		pluginSocketPath = DefaultPluginSocketPath
+	}
+
+	go d.serveGRPC(ctx, pluginSocketPath)
 	go d.CleanupStaleStateContinuously(ctx)
+	go d.reconcileStaleStatePeriodically(ctx)
 
 	return d, nil
 }
 
 func (d *driver) Shutdown(ctx context.Context) error {
+	d.health.setReady(false)
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		err := d.nasclient.Get(ctx)
 		if err != nil {
@@ -100,96 +180,238 @@ func (d *driver) Shutdown(ctx context.Context) error {
 	})
 }
 
-func (d *driver) NodePrepareResource(ctx context.Context, req *drapbv1.NodePrepareResourceRequest) (*drapbv1.NodePrepareResourceResponse, error) {
+// RegisterGRPCServer wires this driver up as the v1alpha3 DRA NodeServer
+// implementation on the provided gRPC server.
+func (d *driver) RegisterGRPCServer(grpcServer *grpc.Server) {
+	drapbv1.RegisterNodeServer(grpcServer, d)
+}
+
+func (d *driver) NodePrepareResources(ctx context.Context, req *drapbv1.NodePrepareResourcesRequest) (*drapbv1.NodePrepareResourcesResponse, error) {
 	d.Lock()
 	defer d.Unlock()
 
-	klog.Infof("NodePrepareResource is called: request: %+v", req)
+	klog.Infof("NodePrepareResources is called: request: %+v", req)
+
+	resp := &drapbv1.NodePrepareResourcesResponse{
+		Claims: make(map[string]*drapbv1.NodePrepareResourceResponse),
+	}
 
-	isPrepared, prepared, err := d.IsPrepared(ctx, req.ClaimUid)
+	err := d.nasclient.Get(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error checking if claim is already prepared: %w", err)
+		return nil, fmt.Errorf("error retrieving node allocation state: %w", err)
+	}
+
+	var needsPrepare []string
+	for _, claim := range req.Claims {
+		if _, exists := d.nascrd.Spec.PreparedClaims[claim.Uid]; exists {
+			devices := d.state.cdi.GetClaimDevices(claim.Uid)
+			klog.Infof("Returning cached devices for claim '%v': %s", claim.Uid, devices)
+			resp.Claims[claim.Uid] = &drapbv1.NodePrepareResourceResponse{CDIDevices: devices}
+			continue
+		}
+		needsPrepare = append(needsPrepare, claim.Uid)
 	}
 
-	if isPrepared {
-		klog.Infof("Returning cached devices for claim '%v': %s", req.ClaimUid, prepared)
-		return &drapbv1.NodePrepareResourceResponse{CdiDevices: prepared}, nil
+	if len(needsPrepare) == 0 {
+		return resp, nil
 	}
 
-	prepared, err = d.Prepare(ctx, req.ClaimUid)
+	prepared, prepareErrs, err := d.prepare(ctx, needsPrepare)
 	if err != nil {
-		return nil, fmt.Errorf("error preparing devices for claim %v: %w", req.ClaimUid, err)
+		return nil, fmt.Errorf("error preparing devices for claims %v: %w", needsPrepare, err)
 	}
 
-	klog.Infof("Returning newly prepared devices for claim '%v': %s", req.ClaimUid, prepared)
-	return &drapbv1.NodePrepareResourceResponse{CdiDevices: prepared}, nil
+	for claimUID, devices := range prepared {
+		klog.Infof("Returning newly prepared devices for claim '%v': %s", claimUID, devices)
+		resp.Claims[claimUID] = &drapbv1.NodePrepareResourceResponse{CDIDevices: devices}
+	}
+	for claimUID, claimErr := range prepareErrs {
+		klog.Errorf("Error preparing devices for claim '%v': %v", claimUID, claimErr)
+		resp.Claims[claimUID] = &drapbv1.NodePrepareResourceResponse{Error: claimErr.Error()}
+	}
+
+	return resp, nil
 }
 
-func (d *driver) NodeUnprepareResource(ctx context.Context, req *drapbv1.NodeUnprepareResourceRequest) (*drapbv1.NodeUnprepareResourceResponse, error) {
-	// We don't upprepare as part of NodeUnprepareResource, we do it
+func (d *driver) NodeUnprepareResources(ctx context.Context, req *drapbv1.NodeUnprepareResourcesRequest) (*drapbv1.NodeUnprepareResourcesResponse, error) {
+	// We don't unprepare as part of NodeUnprepareResources, we do it
 	// asynchronously when the claims themselves are deleted and the
 	// AllocatedClaim has been removed.
-	return &drapbv1.NodeUnprepareResourceResponse{}, nil
+	resp := &drapbv1.NodeUnprepareResourcesResponse{
+		Claims: make(map[string]*drapbv1.NodeUnprepareResourceResponse),
+	}
+	for _, claim := range req.Claims {
+		resp.Claims[claim.Uid] = &drapbv1.NodeUnprepareResourceResponse{}
+	}
+	return resp, nil
 }
 
-func (d *driver) IsPrepared(ctx context.Context, claimUID string) (bool, []string, error) {
-	err := d.nasclient.Get(ctx)
-	if err != nil {
-		return false, nil, err
+// NodeListAndWatchResources streams the node's current ResourceModel,
+// derived from DeviceState, to the caller, re-sending it whenever the
+// underlying NodeAllocationState CRD changes.
+func (d *driver) NodeListAndWatchResources(req *drapbv1.NodeListAndWatchResourcesRequest, stream drapbv1.Node_NodeListAndWatchResourcesServer) error {
+	sub, unsubscribe := d.subscribeResourceModel()
+	defer unsubscribe()
+
+	if err := stream.Send(&drapbv1.NodeListAndWatchResourcesResponse{Resources: []*resourceapi.ResourceModel{d.currentResourceModel()}}); err != nil {
+		return fmt.Errorf("error sending initial resource model: %w", err)
 	}
-	if _, exists := d.nascrd.Spec.PreparedClaims[claimUID]; exists {
-		return true, d.state.cdi.GetClaimDevices(claimUID), nil
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case model, ok := <-sub:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(&drapbv1.NodeListAndWatchResourcesResponse{Resources: []*resourceapi.ResourceModel{model}}); err != nil {
+				return fmt.Errorf("error sending updated resource model: %w", err)
+			}
+		}
 	}
-	return false, nil, nil
 }
 
-func (d *driver) Prepare(ctx context.Context, claimUID string) ([]string, error) {
-	var err error
-	var prepared []string
-	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		err = d.nasclient.Get(ctx)
-		if err != nil {
-			return err
+// currentResourceModel returns the ResourceModel DeviceState currently
+// reports for this node's allocatable devices.
+func (d *driver) currentResourceModel() *resourceapi.ResourceModel {
+	d.Lock()
+	defer d.Unlock()
+	return d.state.GetResourceModel()
+}
+
+func (d *driver) subscribeResourceModel() (<-chan *resourceapi.ResourceModel, func()) {
+	d.watchLock.Lock()
+	defer d.watchLock.Unlock()
+
+	id := d.nextSubID
+	d.nextSubID++
+	ch := make(chan *resourceapi.ResourceModel, 1)
+	d.watchSubs[id] = ch
+
+	return ch, func() {
+		d.watchLock.Lock()
+		defer d.watchLock.Unlock()
+		if sub, exists := d.watchSubs[id]; exists {
+			delete(d.watchSubs, id)
+			close(sub)
 		}
+	}
+}
 
-		prepared, err = d.state.Prepare(ctx, claimUID, d.nascrd.Spec.AllocatedClaims[claimUID])
-		if err != nil {
-			return err
+func (d *driver) broadcastResourceModel() {
+	model := d.currentResourceModel()
+
+	d.watchLock.Lock()
+	defer d.watchLock.Unlock()
+	for id, sub := range d.watchSubs {
+		select {
+		case sub <- model:
+		default:
+			klog.Warningf("Dropping resource model update for slow NodeListAndWatchResources subscriber %d", id)
 		}
+	}
+}
+
+// prepare prepares a batch of claims under a single lock acquisition,
+// folding the resulting CRD update into one nasclient.Update call. A failure
+// to prepare an individual claim does not abort the batch: it is recorded in
+// the returned error map so the caller can still report cached/successful
+// claims, and successfully prepared claims are still persisted. The returned
+// error is reserved for failures that prevent the batch from making any
+// progress at all (e.g. fetching or updating the CRD itself).
+func (d *driver) prepare(ctx context.Context, claimUIDs []string) (map[string][]string, map[string]error, error) {
+	start := time.Now()
+
+	prepared := make(map[string][]string)
+	claimErrs := make(map[string]error)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		prepared = make(map[string][]string)
+		claimErrs = make(map[string]error)
 
-		err = d.nasclient.Update(ctx, d.state.GetUpdatedSpec(&d.nascrd.Spec))
+		err := d.nasclient.Get(ctx)
 		if err != nil {
 			return err
 		}
 
-		return nil
+		for _, claimUID := range claimUIDs {
+			devices, err := d.state.Prepare(ctx, claimUID, d.nascrd.Spec.AllocatedClaims[claimUID])
+			if err != nil {
+				claimErrs[claimUID] = fmt.Errorf("error preparing devices for claim %v: %w", claimUID, err)
+				continue
+			}
+			prepared[claimUID] = devices
+		}
+
+		return d.nasclient.Update(ctx, d.state.GetUpdatedSpec(&d.nascrd.Spec))
 	})
+
+	outcomes := make(map[string]error, len(claimUIDs))
+	for _, claimUID := range claimUIDs {
+		if err != nil {
+			// The whole batch failed before or while persisting the CRD
+			// update, so every claim in it -- including ones that
+			// individually succeeded above -- counts as a failure.
+			outcomes[claimUID] = err
+			continue
+		}
+		outcomes[claimUID] = claimErrs[claimUID]
+	}
+	d.health.metrics.observePrepare(time.Since(start), outcomes)
+
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return prepared, nil
+	return prepared, claimErrs, nil
 }
 
-func (d *driver) Unprepare(ctx context.Context, claimUID string) error {
+func (d *driver) unprepare(ctx context.Context, claimUIDs []string) (map[string]error, error) {
+	start := time.Now()
+
+	claimErrs := make(map[string]error)
 	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		claimErrs = make(map[string]error)
+
 		err := d.nasclient.Get(ctx)
 		if err != nil {
 			return err
 		}
 
-		err = d.state.Unprepare(ctx, claimUID)
-		if err != nil {
-			return err
+		for _, claimUID := range claimUIDs {
+			if err := d.state.Unprepare(ctx, claimUID); err != nil {
+				claimErrs[claimUID] = err
+				continue
+			}
 		}
 
-		err = d.nasclient.Update(ctx, d.state.GetUpdatedSpec(&d.nascrd.Spec))
+		return d.nasclient.Update(ctx, d.state.GetUpdatedSpec(&d.nascrd.Spec))
+	})
+
+	outcomes := make(map[string]error, len(claimUIDs))
+	for _, claimUID := range claimUIDs {
 		if err != nil {
-			return err
+			outcomes[claimUID] = err
+			continue
 		}
+		outcomes[claimUID] = claimErrs[claimUID]
+	}
+	d.health.metrics.observeUnprepare(time.Since(start), outcomes)
 
-		return nil
-	})
 	if err != nil {
+		return nil, err
+	}
+	return claimErrs, nil
+}
+
+// Unprepare unprepares a single claim. It is a thin wrapper around the
+// batched unprepare path, used by the stale-state cleanup loop where claims
+// are unprepared one at a time as they're discovered.
+func (d *driver) Unprepare(ctx context.Context, claimUID string) error {
+	errs, err := d.unprepare(ctx, []string{claimUID})
+	if err != nil {
+		return err
+	}
+	if err, exists := errs[claimUID]; exists {
 		return err
 	}
 	return nil
@@ -210,6 +432,32 @@ func (d *driver) CleanupStaleStateContinuously(ctx context.Context) {
 	}
 }
 
+// reconcileStaleStatePeriodically is a belt-and-suspenders safety net
+// alongside the watch-based CleanupStaleStateContinuously. Watches can
+// silently miss events across apiserver disconnects or resource-version
+// expirations, so this unconditionally re-reconciles PreparedClaims against
+// AllocatedClaims on a fixed interval.
+func (d *driver) reconcileStaleStatePeriodically(ctx context.Context) {
+	ticker := time.NewTicker(d.reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := d.cleanupStaleStateOnce(ctx); err != nil {
+				klog.Errorf("Error during periodic stale state reconciliation: %v", err)
+				continue
+			}
+			// A successful periodic reconcile proves the driver is making
+			// forward progress even if the watch happens to be
+			// disconnected at this moment.
+			d.health.recordWatchSuccess()
+		}
+	}
+}
+
 func (d *driver) cleanupStaleStateOnce(ctx context.Context) (string, error) {
 	listOptions := metav1.ListOptions{
 		FieldSelector: fmt.Sprintf("metadata.name=%s", d.nascrd.Name),
@@ -247,10 +495,14 @@ func (d *driver) cleanupStaleStateContinuously(ctx context.Context, resourceVers
 
 	watcher, err := d.nasclient.Watch(ctx, watchOptions)
 	if err != nil {
+		d.health.setWatchConnected(false)
 		return fmt.Errorf("error setting up watch to cleanup allocations: %w", err)
 	}
 	defer watcher.Stop()
 
+	d.health.setWatchConnected(true)
+	defer d.health.setWatchConnected(false)
+
 	for event := range watcher.ResultChan() {
 		if event.Type != watch.Modified {
 			continue
@@ -265,12 +517,20 @@ func (d *driver) cleanupStaleStateContinuously(ctx context.Context, resourceVers
 		if err != nil {
 			return err
 		}
+
+		d.health.recordWatchSuccess()
+		d.broadcastResourceModel()
 	}
 
 	return nil
 }
 
 func (d *driver) cleanupStaleState(ctx context.Context, nas *nascrd.NodeAllocationState) error {
+	// Refresh the prepared-claims gauge from the authoritative CRD state on
+	// every reconcile (watch-driven or periodic) instead of accumulating
+	// prepare/unprepare deltas, so it's correct even right after a restart.
+	d.health.metrics.setPreparedClaims(len(nas.Spec.PreparedClaims))
+
 	var wg sync.WaitGroup
 	var errorChans []chan error
 	errorCounts := make(chan int)
@@ -324,34 +584,312 @@ func (d *driver) cleanupStaleState(ctx context.Context, nas *nascrd.NodeAllocati
 
 func (d *driver) cleanupClaimAllocations(ctx context.Context, nas *nascrd.NodeAllocationState, wg *sync.WaitGroup) chan error {
 	errors := make(chan error)
+
+	now := time.Now()
+	d.staleLock.Lock()
+	for claimUID := range nas.Spec.PreparedClaims {
+		if _, exists := nas.Spec.AllocatedClaims[claimUID]; exists {
+			delete(d.staleSince, claimUID)
+			continue
+		}
+		if _, tracked := d.staleSince[claimUID]; !tracked {
+			d.staleSince[claimUID] = now
+		}
+	}
+	d.staleLock.Unlock()
+
 	for claimUID := range nas.Spec.PreparedClaims {
-		if _, exists := nas.Spec.AllocatedClaims[claimUID]; !exists {
-			wg.Add(1)
-			go func(claimUID string) {
-				defer wg.Done()
-				klog.Infof("Attempting to unprepare resources for claim %v", claimUID)
-				err := d.Unprepare(ctx, claimUID)
-				if err != nil {
-					errors <- fmt.Errorf("error unpreparing resources for claim %v: %w", claimUID, err)
-					return
-				}
-				klog.Infof("Successfully unprepared resources for claim %v", claimUID)
-			}(claimUID)
+		if _, exists := nas.Spec.AllocatedClaims[claimUID]; exists {
+			continue
+		}
+
+		d.staleLock.Lock()
+		firstObservedStale := d.staleSince[claimUID]
+		d.staleLock.Unlock()
+
+		if time.Since(firstObservedStale) < d.staleClaimGracePeriod {
+			klog.V(4).Infof("Claim %v observed stale but still within grace period, deferring unprepare", claimUID)
+			continue
 		}
+
+		wg.Add(1)
+		go func(claimUID string) {
+			defer wg.Done()
+			klog.Infof("Attempting to unprepare resources for claim %v", claimUID)
+			err := d.Unprepare(ctx, claimUID)
+			if err != nil {
+				errors <- fmt.Errorf("error unpreparing resources for claim %v: %w", claimUID, err)
+				return
+			}
+			klog.Infof("Successfully unprepared resources for claim %v", claimUID)
+			d.health.metrics.staleClaimsUnprepared.Inc()
+			d.staleLock.Lock()
+			delete(d.staleSince, claimUID)
+			d.staleLock.Unlock()
+		}(claimUID)
 	}
 	return errors
 }
 
 func (d *driver) cleanupCDIFiles(nas *nascrd.NodeAllocationState, wg *sync.WaitGroup) chan error {
-	// TODO: implement loop to remove CDI files from the CDI path for claimUIDs
-	// that have been removed from the AllocatedClaims map.
+	onRemoved := func(string) { d.health.metrics.staleCDIFilesRemoved.Inc() }
+	return cleanupStaleCDIFiles(d.state.cdi.SpecDir(), allocatedClaimUIDSet(nas), wg, onRemoved)
+}
+
+// cleanupStaleCDIFiles walks specDir and removes any CDI spec file whose
+// claim UID (recovered from the file name) is not present in
+// allocatedClaims. It is factored out of cleanupCDIFiles so it can be
+// exercised directly in tests without a full DeviceState. onRemoved, if
+// non-nil, is called once per file successfully removed.
+func cleanupStaleCDIFiles(specDir string, allocatedClaims map[string]bool, wg *sync.WaitGroup, onRemoved func(claimUID string)) chan error {
 	errors := make(chan error)
+
+	entries, err := os.ReadDir(specDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// No claim has ever been prepared on this node yet, so there's
+			// nothing to clean up.
+			return errors
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errors <- fmt.Errorf("error reading CDI spec directory %q: %w", specDir, err)
+		}()
+		return errors
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		claimUID, ok := cdiClaimUIDFromSpecFileName(entry.Name())
+		if !ok || allocatedClaims[claimUID] {
+			continue
+		}
+
+		wg.Add(1)
+		go func(name, claimUID string) {
+			defer wg.Done()
+			path := filepath.Join(specDir, name)
+			klog.Infof("Removing stale CDI spec for claim %v: %s", claimUID, path)
+			if err := os.Remove(path); err != nil {
+				errors <- fmt.Errorf("error removing stale CDI spec %q for claim %v: %w", path, claimUID, err)
+				return
+			}
+			if onRemoved != nil {
+				onRemoved(claimUID)
+			}
+		}(entry.Name(), claimUID)
+	}
+
 	return errors
 }
 
+// cdiSpecFileName returns the file name DeviceState.cdi writes a claim's CDI
+// spec under. cdiClaimUIDFromSpecFileName is its exact inverse.
+func cdiSpecFileName(claimUID string) string {
+	return fmt.Sprintf("%s-%s_%s%s", cdiVendor, cdiClass, claimUID, cdiSpecFileSuffix)
+}
+
+// cdiClaimUIDFromSpecFileName recovers the claim UID this driver encoded
+// into a CDI spec file name via cdiSpecFileName, or ok == false if name
+// doesn't follow that convention (e.g. a spec file belonging to another
+// vendor/class, or a stray file in the spec directory).
+func cdiClaimUIDFromSpecFileName(name string) (string, bool) {
+	prefix := fmt.Sprintf("%s-%s_", cdiVendor, cdiClass)
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, cdiSpecFileSuffix) {
+		return "", false
+	}
+	claimUID := strings.TrimSuffix(strings.TrimPrefix(name, prefix), cdiSpecFileSuffix)
+	if claimUID == "" {
+		return "", false
+	}
+	return claimUID, true
+}
+
+// allocatedClaimUIDSet returns the set of claim UIDs currently present in
+// nas.Spec.AllocatedClaims.
+func allocatedClaimUIDSet(nas *nascrd.NodeAllocationState) map[string]bool {
+	allocated := make(map[string]bool, len(nas.Spec.AllocatedClaims))
+	for claimUID := range nas.Spec.AllocatedClaims {
+		allocated[claimUID] = true
+	}
+	return allocated
+}
+
 func (d *driver) cleanupMpsControlDaemonArtifacts(nas *nascrd.NodeAllocationState, wg *sync.WaitGroup) chan error {
-	// TODO: implement loop to remove mpsControlDaemon folders from the mps
-	// path for claimUIDs that have been removed from the AllocatedClaims map.
+	onRemoved := func(string) { d.health.metrics.staleMpsDirsRemoved.Inc() }
+	// The pipe/pid layout inside a claim's MPS directory is owned by
+	// d.state.mpsControlDaemon (the same subsystem RootDir() comes from),
+	// not invented here, so stopMpsControlDaemon can never drift from what
+	// actually gets written on disk.
+	stopFn := func(claimDir string) error {
+		return stopMpsControlDaemon(d.state.mpsControlDaemon.PipeDir(claimDir), d.state.mpsControlDaemon.PidFile(claimDir))
+	}
+	return cleanupStaleMpsControlDaemonArtifacts(d.state.mpsControlDaemon.RootDir(), allocatedClaimUIDSet(nas), wg, stopFn, onRemoved)
+}
+
+// cleanupStaleMpsControlDaemonArtifacts walks rootDir, which contains one
+// subdirectory per claim keyed by claim UID, stops the MPS control daemon
+// running for any claim UID absent from allocatedClaims, and removes its
+// pipe/log directory. stopFn is factored out so this can be exercised in
+// tests without spawning a real MPS control daemon. onRemoved, if non-nil,
+// is called once per directory successfully removed.
+func cleanupStaleMpsControlDaemonArtifacts(rootDir string, allocatedClaims map[string]bool, wg *sync.WaitGroup, stopFn func(claimDir string) error, onRemoved func(claimUID string)) chan error {
 	errors := make(chan error)
+
+	entries, err := os.ReadDir(rootDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// No claim has ever started an MPS control daemon on this node
+			// yet, so there's nothing to clean up.
+			return errors
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errors <- fmt.Errorf("error reading MPS root directory %q: %w", rootDir, err)
+		}()
+		return errors
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		claimUID := entry.Name()
+		if allocatedClaims[claimUID] {
+			continue
+		}
+
+		wg.Add(1)
+		go func(claimUID string) {
+			defer wg.Done()
+
+			claimDir := filepath.Join(rootDir, claimUID)
+
+			klog.Infof("Attempting to stop MPS control daemon for claim %v", claimUID)
+			if err := stopFn(claimDir); err != nil {
+				errors <- fmt.Errorf("error stopping MPS control daemon for claim %v: %w", claimUID, err)
+				return
+			}
+
+			klog.Infof("Removing stale MPS artifacts for claim %v: %s", claimUID, claimDir)
+			if err := os.RemoveAll(claimDir); err != nil {
+				errors <- fmt.Errorf("error removing MPS artifacts %q for claim %v: %w", claimDir, claimUID, err)
+				return
+			}
+			if onRemoved != nil {
+				onRemoved(claimUID)
+			}
+		}(claimUID)
+	}
+
 	return errors
 }
+
+// stopMpsControlDaemon asks the MPS control daemon listening on pipeDir to
+// quit cleanly, falling back to SIGTERM and then SIGKILL if it doesn't
+// respond in time. pidPath and pipeDir must come from the same
+// mpsControlDaemon subsystem that wrote them (see cleanupMpsControlDaemonArtifacts),
+// not from an invented convention. The common case for an orphaned claim is
+// that the daemon (or even its pid file) is already gone -- that is treated
+// as success, not an error, so the caller still removes claimDir.
+func stopMpsControlDaemon(pipeDir, pidPath string) error {
+	pid, err := readMpsControlDaemonPid(pidPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			klog.V(4).Infof("No MPS control daemon pid file at %q, nothing to stop", pidPath)
+			return nil
+		}
+		return err
+	}
+
+	if !processRunning(pid) {
+		return nil
+	}
+
+	if err := quitMpsControlDaemon(pipeDir, mpsQuitTimeout); err != nil {
+		klog.Warningf("Graceful quit of MPS control daemon in %q failed, falling back to signals: %v", pipeDir, err)
+		return terminateProcess(pid, mpsTermTimeout)
+	}
+
+	if !processExited(pid, mpsQuitTimeout) {
+		klog.Warningf("MPS control daemon (pid %d) still running after quit, falling back to signals", pid)
+		return terminateProcess(pid, mpsTermTimeout)
+	}
+
+	return nil
+}
+
+func quitMpsControlDaemon(pipeDir string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "nvidia-cuda-mps-control")
+	cmd.Env = append(os.Environ(), fmt.Sprintf("CUDA_MPS_PIPE_DIRECTORY=%s", pipeDir))
+	cmd.Stdin = strings.NewReader("quit\n")
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error sending quit command to MPS control daemon: %w", err)
+	}
+	return nil
+}
+
+func readMpsControlDaemonPid(pidPath string) (int, error) {
+	data, err := os.ReadFile(pidPath)
+	if err != nil {
+		// Wrapping with %w keeps errors.Is(err, os.ErrNotExist) true for
+		// the caller, which treats a missing pid file as "daemon not
+		// running" rather than an error. (os.IsNotExist does not see
+		// through this wrap.)
+		return 0, fmt.Errorf("error reading MPS control daemon pid file: %w", err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("error parsing MPS control daemon pid file: %w", err)
+	}
+
+	return pid, nil
+}
+
+// terminateProcess sends SIGTERM to pid and waits up to timeout for it to
+// exit, escalating to SIGKILL if it's still running afterwards.
+func terminateProcess(pid int, timeout time.Duration) error {
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		if err == syscall.ESRCH {
+			return nil
+		}
+		return fmt.Errorf("error sending SIGTERM to pid %d: %w", pid, err)
+	}
+
+	if processExited(pid, timeout) {
+		return nil
+	}
+
+	klog.Warningf("MPS control daemon (pid %d) did not exit after SIGTERM, sending SIGKILL", pid)
+	if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return fmt.Errorf("error sending SIGKILL to pid %d: %w", pid, err)
+	}
+	return nil
+}
+
+// processRunning reports whether pid refers to a live process.
+func processRunning(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
+
+func processExited(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if err := syscall.Kill(pid, 0); err != nil {
+			return true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return false
+}
@@ -0,0 +1,292 @@
+/*
+ * Copyright (c) 2023, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultHealthzAddress is the address the metrics/health server listens
+	// on when Config doesn't override it.
+	DefaultHealthzAddress = ":8080"
+
+	// DefaultWatchStalenessThreshold is how long the NodeAllocationState
+	// watch can stay disconnected, with no liveness signal from a watch
+	// event or a periodic reconcile, before readyz starts reporting
+	// not-ready. A connected watch keeps readyz ready however long it idles.
+	DefaultWatchStalenessThreshold = 2 * time.Minute
+)
+
+// driverMetrics holds the Prometheus instruments the driver reports through
+// the health server's /metrics endpoint.
+type driverMetrics struct {
+	preparedClaims        prometheus.Gauge
+	prepareTotal          *prometheus.CounterVec
+	unprepareTotal        *prometheus.CounterVec
+	prepareDuration       prometheus.Histogram
+	unprepareDuration     prometheus.Histogram
+	watchConnected        prometheus.Gauge
+	staleClaimsUnprepared prometheus.Counter
+	staleCDIFilesRemoved  prometheus.Counter
+	staleMpsDirsRemoved   prometheus.Counter
+}
+
+func newDriverMetrics(registerer prometheus.Registerer) *driverMetrics {
+	factory := promauto.With(registerer)
+
+	return &driverMetrics{
+		preparedClaims: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: "dra_gpu",
+			Name:      "prepared_claims",
+			Help:      "Number of claims currently prepared on this node.",
+		}),
+		prepareTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "dra_gpu",
+			Name:      "prepare_total",
+			Help:      "Total per-claim NodePrepareResources outcomes, partitioned by error class (\"success\" when the claim was prepared).",
+		}, []string{"error_class"}),
+		unprepareTotal: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "dra_gpu",
+			Name:      "unprepare_total",
+			Help:      "Total per-claim unprepare outcomes from the stale-state cleanup, partitioned by error class (\"success\" when the claim was unprepared).",
+		}, []string{"error_class"}),
+		prepareDuration: factory.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "dra_gpu",
+			Name:      "prepare_duration_seconds",
+			Help:      "Latency of a batched claim preparation call.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		unprepareDuration: factory.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "dra_gpu",
+			Name:      "unprepare_duration_seconds",
+			Help:      "Latency of a batched claim unpreparation call.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+		watchConnected: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: "dra_gpu",
+			Name:      "nas_watch_connected",
+			Help:      "Whether the NodeAllocationState watch is currently connected (1) or not (0).",
+		}),
+		staleClaimsUnprepared: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "dra_gpu",
+			Name:      "stale_claims_unprepared_total",
+			Help:      "Total stale claims unprepared by the cleanup loop.",
+		}),
+		staleCDIFilesRemoved: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "dra_gpu",
+			Name:      "stale_cdi_files_removed_total",
+			Help:      "Total orphan CDI spec files removed by the cleanup loop.",
+		}),
+		staleMpsDirsRemoved: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "dra_gpu",
+			Name:      "stale_mps_dirs_removed_total",
+			Help:      "Total orphan MPS control daemon directories removed by the cleanup loop.",
+		}),
+	}
+}
+
+// observePrepare records one prepare_total outcome per claim in outcomes
+// (keyed by claim UID, nil value meaning that claim was prepared
+// successfully), so a batch that partially fails doesn't hide the claims
+// that actually errored behind a single batch-level "error" count.
+func (m *driverMetrics) observePrepare(duration time.Duration, outcomes map[string]error) {
+	m.prepareDuration.Observe(duration.Seconds())
+	for _, err := range outcomes {
+		m.prepareTotal.WithLabelValues(errorClass(err)).Inc()
+	}
+}
+
+// observeUnprepare is observePrepare's unprepare counterpart. Claims are
+// unprepared by the stale-state cleanup, not by NodeUnprepareResources.
+func (m *driverMetrics) observeUnprepare(duration time.Duration, outcomes map[string]error) {
+	m.unprepareDuration.Observe(duration.Seconds())
+	for _, err := range outcomes {
+		m.unprepareTotal.WithLabelValues(errorClass(err)).Inc()
+	}
+}
+
+// errorClass buckets err into a coarse, bounded-cardinality label value for
+// the prepare/unprepare counters, so failures can be broken down by kind
+// (e.g. an apiserver conflict vs. an actual device-preparation failure)
+// without exploding into one label value per distinct error string.
+func errorClass(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case apierrors.IsConflict(err):
+		return "conflict"
+	case apierrors.IsNotFound(err):
+		return "not_found"
+	default:
+		return "internal"
+	}
+}
+
+// setPreparedClaims sets the prepared-claims gauge to the authoritative
+// count from NodeAllocationState.Spec.PreparedClaims, rather than
+// accumulating prepare/unprepare deltas. Deltas drift (a missed decrement
+// compounds forever) and start every process restart from zero despite
+// claims already being prepared, so this is refreshed from nas.Spec on every
+// stale-state reconcile instead.
+func (m *driverMetrics) setPreparedClaims(count int) {
+	m.preparedClaims.Set(float64(count))
+}
+
+// healthServer exposes Prometheus metrics and /healthz, /readyz endpoints
+// over HTTP so operators get the same visibility into the driver that
+// standard Kubernetes probes expect, instead of relying on klog output.
+type healthServer struct {
+	metrics *driverMetrics
+	server  *http.Server
+
+	mu sync.Mutex
+	// ready reflects the NodeAllocationStateStatusReady transition
+	// performed in NewDriver.
+	ready bool
+	// watchConnected is true while the NAS CRD watch has an open connection
+	// to the apiserver. A node-local NAS CRD can legitimately go unchanged
+	// for long stretches (claims are only prepared/unprepared occasionally),
+	// so readiness is gated on the watch being connected, not on having
+	// recently observed an event.
+	watchConnected bool
+	// lastLivenessSignal is a fallback staleness clock for when the watch
+	// is reported disconnected: the periodic reconcile loop and watch
+	// events both refresh it, so a brief disconnect doesn't immediately
+	// flip readiness so long as something is still proving liveness.
+	lastLivenessSignal      time.Time
+	watchStalenessThreshold time.Duration
+}
+
+func newHealthServer(address string, watchStalenessThreshold time.Duration) *healthServer {
+	if address == "" {
+		address = DefaultHealthzAddress
+	}
+	if watchStalenessThreshold <= 0 {
+		watchStalenessThreshold = DefaultWatchStalenessThreshold
+	}
+
+	registry := prometheus.NewRegistry()
+	h := &healthServer{
+		metrics:                 newDriverMetrics(registry),
+		watchStalenessThreshold: watchStalenessThreshold,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/readyz", h.handleReadyz)
+
+	h.server = &http.Server{
+		Addr:    address,
+		Handler: mux,
+	}
+
+	return h
+}
+
+func (h *healthServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if h.isReady() {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Error(w, "not ready", http.StatusServiceUnavailable)
+}
+
+func (h *healthServer) isReady() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.ready {
+		return false
+	}
+	if h.watchConnected {
+		return true
+	}
+	// The watch is currently disconnected (reconnecting after a transient
+	// apiserver error); only flip to not-ready once nothing -- neither the
+	// watch nor the periodic reconcile loop -- has proven liveness for a
+	// full staleness threshold.
+	return time.Since(h.lastLivenessSignal) < h.watchStalenessThreshold
+}
+
+// setReady flips overall readiness, reflecting the
+// NodeAllocationStateStatusReady transition performed in NewDriver.
+func (h *healthServer) setReady(ready bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ready = ready
+	if ready {
+		h.lastLivenessSignal = time.Now()
+	}
+}
+
+// setWatchConnected records whether the NAS CRD watch currently has an open
+// connection to the apiserver. This is the primary signal readyz gates on:
+// an idle-but-connected watch is healthy even if the CRD hasn't changed
+// recently.
+func (h *healthServer) setWatchConnected(connected bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.watchConnected = connected
+	if connected {
+		h.lastLivenessSignal = time.Now()
+		h.metrics.watchConnected.Set(1)
+	} else {
+		h.metrics.watchConnected.Set(0)
+	}
+}
+
+// recordWatchSuccess marks that something -- a watch event or a periodic
+// reconcile pass -- just proved the driver is making forward progress,
+// resetting the staleness clock readyz falls back to while the watch is
+// disconnected.
+func (h *healthServer) recordWatchSuccess() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastLivenessSignal = time.Now()
+}
+
+// Start serves metrics/health endpoints until ctx is done.
+func (h *healthServer) Start(ctx context.Context) {
+	go func() {
+		klog.Infof("Starting metrics/health server on %s", h.server.Addr)
+		if err := h.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("metrics/health server exited with error: %v", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := h.server.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("error shutting down metrics/health server: %v", err)
+		}
+	}()
+}
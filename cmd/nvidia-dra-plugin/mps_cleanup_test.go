@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2023, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestCleanupStaleMpsControlDaemonArtifacts(t *testing.T) {
+	rootDir := t.TempDir()
+
+	live := []string{"claim-live-1"}
+	stale := []string{"claim-stale-1", "claim-stale-2"}
+
+	allocated := make(map[string]bool)
+	for _, claimUID := range live {
+		allocated[claimUID] = true
+		mkdirOrFatal(t, filepath.Join(rootDir, claimUID))
+	}
+	for _, claimUID := range stale {
+		mkdirOrFatal(t, filepath.Join(rootDir, claimUID))
+	}
+
+	var wg sync.WaitGroup
+	errs := cleanupStaleMpsControlDaemonArtifacts(rootDir, allocated, &wg, func(string) error { return nil }, nil)
+
+	var errCount int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range errs {
+			t.Errorf("unexpected error cleaning up MPS artifacts: %v", err)
+			errCount++
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+	<-done
+
+	if errCount != 0 {
+		t.Fatalf("expected no errors, got %d", errCount)
+	}
+
+	for _, claimUID := range live {
+		if _, err := os.Stat(filepath.Join(rootDir, claimUID)); err != nil {
+			t.Errorf("expected live MPS directory for claim %v to remain: %v", claimUID, err)
+		}
+	}
+	for _, claimUID := range stale {
+		if _, err := os.Stat(filepath.Join(rootDir, claimUID)); !os.IsNotExist(err) {
+			t.Errorf("expected stale MPS directory for claim %v to be removed, stat err: %v", claimUID, err)
+		}
+	}
+}
+
+func TestCleanupStaleMpsControlDaemonArtifactsStopFailureLeavesDirectory(t *testing.T) {
+	rootDir := t.TempDir()
+	claimUID := "claim-stuck"
+	mkdirOrFatal(t, filepath.Join(rootDir, claimUID))
+
+	var wg sync.WaitGroup
+	stopErr := errors.New("daemon would not quit")
+	errs := cleanupStaleMpsControlDaemonArtifacts(rootDir, map[string]bool{}, &wg, func(string) error { return stopErr }, nil)
+
+	var errCount int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range errs {
+			errCount++
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+	<-done
+
+	if errCount != 1 {
+		t.Fatalf("expected exactly 1 error, got %d", errCount)
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, claimUID)); err != nil {
+		t.Errorf("expected MPS directory to remain after a failed stop: %v", err)
+	}
+}
+
+func TestCleanupStaleMpsControlDaemonArtifactsMissingRootDir(t *testing.T) {
+	rootDir := filepath.Join(t.TempDir(), "never-created")
+
+	var wg sync.WaitGroup
+	errs := cleanupStaleMpsControlDaemonArtifacts(rootDir, map[string]bool{}, &wg, func(string) error { return nil }, nil)
+
+	var errCount int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range errs {
+			t.Errorf("unexpected error cleaning up an MPS root dir that was never created: %v", err)
+			errCount++
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+	<-done
+
+	if errCount != 0 {
+		t.Fatalf("expected no errors, got %d", errCount)
+	}
+}
+
+func TestStopMpsControlDaemonNoPidFile(t *testing.T) {
+	claimDir := t.TempDir()
+	pipeDir := filepath.Join(claimDir, "pipe")
+	pidPath := filepath.Join(claimDir, "pid")
+
+	if err := stopMpsControlDaemon(pipeDir, pidPath); err != nil {
+		t.Fatalf("expected a claim dir with no pid file to be treated as already stopped, got: %v", err)
+	}
+}
+
+// TestStopMpsControlDaemonTerminatesRunningProcess pins stopMpsControlDaemon's
+// signal fallback against a real process: with no MPS control daemon to quit
+// gracefully, it must fall back to SIGTERM (or SIGKILL) and actually end the
+// pid recorded in pidPath, not just no-op because the pid file exists.
+func TestStopMpsControlDaemonTerminatesRunningProcess(t *testing.T) {
+	claimDir := t.TempDir()
+	pipeDir := filepath.Join(claimDir, "pipe")
+	pidPath := filepath.Join(claimDir, "pid")
+
+	cmd := exec.Command("sleep", "300")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("error starting test process: %v", err)
+	}
+	defer cmd.Process.Kill()
+	go cmd.Wait()
+
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(cmd.Process.Pid)), 0644); err != nil {
+		t.Fatalf("error writing test pid file: %v", err)
+	}
+
+	if err := stopMpsControlDaemon(pipeDir, pidPath); err != nil {
+		t.Fatalf("error stopping test process: %v", err)
+	}
+
+	if processRunning(cmd.Process.Pid) {
+		t.Fatalf("expected process %d to have been stopped", cmd.Process.Pid)
+	}
+}
+
+func mkdirOrFatal(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatalf("error creating test directory %q: %v", path, err)
+	}
+}
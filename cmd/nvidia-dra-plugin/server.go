@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2023, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/grpc"
+	"k8s.io/klog/v2"
+)
+
+// DefaultPluginSocketPath is the unix socket the v1alpha3 DRA kubelet
+// plugin gRPC server listens on when config.flags.pluginSocketPath doesn't
+// override it.
+const DefaultPluginSocketPath = "/var/lib/kubelet/plugins/k8s.gpu.resource.nvidia.com/dra.sock"
+
+// serveGRPC listens on socketPath and serves the v1alpha3 DRA NodeServer
+// registered via d.RegisterGRPCServer until ctx is done. It runs as a
+// background goroutine from NewDriver, the same way
+// d.CleanupStaleStateContinuously and d.reconcileStaleStatePeriodically do,
+// so setup errors are logged rather than returned.
+func (d *driver) serveGRPC(ctx context.Context, socketPath string) {
+	if err := os.MkdirAll(filepath.Dir(socketPath), 0750); err != nil {
+		klog.Errorf("error creating plugin socket directory for %q: %v", socketPath, err)
+		return
+	}
+
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		klog.Errorf("error removing stale plugin socket %q: %v", socketPath, err)
+		return
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		klog.Errorf("error listening on plugin socket %q: %v", socketPath, err)
+		return
+	}
+
+	grpcServer := grpc.NewServer()
+	d.RegisterGRPCServer(grpcServer)
+
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+
+	klog.Infof("Serving DRA kubelet plugin v1alpha3 NodeServer on %s", socketPath)
+	if err := grpcServer.Serve(listener); err != nil {
+		klog.Errorf("plugin gRPC server exited with error: %v", err)
+	}
+}